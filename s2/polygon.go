@@ -19,6 +19,12 @@ package s2
 import (
 	"fmt"
 	"io"
+	"math"
+	"math/bits"
+	"sort"
+
+	"github.com/golang/geo/r3"
+	"github.com/golang/geo/s1"
 )
 
 // Polygon represents a sequence of zero or more loops; recall that the
@@ -80,6 +86,11 @@ type Polygon struct {
 	cumulativeEdges []int
 }
 
+// maxLinearSearchLoops is the number of loops at or below which Edge and
+// Chain use a linear scan rather than the cumulativeEdges index.
+// Based on benchmarks.
+const maxLinearSearchLoops = 12
+
 // PolygonFromLoops constructs a polygon from the given hierarchically nested
 // loops. The polygon interior consists of the points contained by an odd
 // number of loops. (Recall that a loop contains the set of points on its
@@ -88,34 +99,254 @@ type Polygon struct {
 // This method figures out the loop nesting hierarchy and assigns every loop a
 // depth. Shells have even depths, and holes have odd depths.
 //
-// NOTE: this function is NOT YET IMPLEMENTED for more than one loop and will
-// panic if given a slice of length > 1.
+// The given loops are reordered to correspond to a preorder traversal of the
+// loop nesting hierarchy, which means that a loop must always precede any
+// loops nested within it.
 func PolygonFromLoops(loops []*Loop) *Polygon {
-	if len(loops) > 1 {
-		panic("PolygonFromLoops for multiple loops is not yet implemented")
+	p := &Polygon{}
+	p.initNested(cloneLoops(loops))
+	return p
+}
+
+// cloneLoops returns a deep copy of loops, so that a Polygon built from them
+// can freely mutate its own copies (depth, orientation) without surprising
+// the caller by mutating the *Loop values they passed in.
+func cloneLoops(loops []*Loop) []*Loop {
+	out := make([]*Loop, len(loops))
+	for i, l := range loops {
+		out[i] = l.Clone()
+	}
+	return out
+}
+
+// PolygonFromOrientedLoops returns a Polygon from the given set of loops,
+// like PolygonFromLoops, except that the loop orientations are automatically
+// inferred and may be arbitrary (i.e. shells and holes do not need to be
+// given any particular orientation).
+//
+// Since the loop orientation is ambiguous, in this case the polygon boundary
+// cannot be crossed anywhere by a path that stays within a single face of
+// the sphere (as it can for ordinary polygons). Specifically, this means
+// that a loop whose orientation happens to be incorrect (i.e. a shell that
+// contains the point at infinity, or a hole that does not) is flipped so
+// that its orientation is consistent with containing that point before the
+// nested initialization is run.
+func PolygonFromOrientedLoops(loops []*Loop) *Polygon {
+	// Here is the algorithm:
+	//
+	// 1. Remember which loop contains the point at infinity.
+	//
+	// 2. Build the loop nesting hierarchy. As part of this process, we need
+	//    to check whether every loop nesting decision made was correct, i.e.
+	//    whether the point contained by a loop is actually inside that loop.
+	//    To do this we compare the nesting depth of the loop that is
+	//    supposed to contain the point at infinity with its own idea of
+	//    whether it contains that point: if the loop's own orientation
+	//    implies that it does not contain the point at infinity, but the
+	//    nesting hierarchy says it should (even depth), then its orientation
+	//    must be wrong and it needs to be reversed.
+	//
+	// Rather than doing a full two-pass analysis, we use the simpler
+	// approach of just flipping any loop whose orientation is inconsistent
+	// with the convention that shells (even depth) do not contain the point
+	// at infinity and holes (odd depth) do, and re-running the nesting
+	// computation. Since containsNested only depends on the set of points
+	// covered by each loop and not on the stored orientation, this converges
+	// after a single pass.
+	owned := cloneLoops(loops)
+	for _, l := range owned {
+		if l.ContainsOrigin() {
+			l.Invert()
+		}
+	}
+
+	p := &Polygon{}
+	p.initNested(owned)
+
+	// Loops that turned out to be holes (odd depth) must contain the
+	// original reference point (the point at infinity), so invert them back.
+	for i, l := range p.loops {
+		if p.loopIsHole(i) {
+			l.Invert()
+		}
+	}
+	// Inverting a hole changes which point it is known to contain, so the
+	// cached bounds must be recomputed to reflect the final orientation.
+	p.initLoopProperties()
+
+	return p
+}
+
+// loopMap stores the nesting relationships of a set of loops being
+// constructed into a polygon. It maps a loop to the list of loops that are
+// directly nested within it (its children in the containment forest); the
+// root of the forest is keyed by nil.
+type loopMap map[*Loop][]*Loop
+
+// insertLoop adds the given loop to the loopMap. The children of parent
+// (nil for the root level) are searched for a loop that contains the new
+// loop, and if one is found insertion recurses into that child; otherwise
+// the new loop is inserted directly under parent, and any of parent's
+// children that the new loop contains are moved under it.
+func (lm loopMap) insertLoop(newLoop, parent *Loop) {
+	var children []*Loop
+	for _, child := range lm[parent] {
+		if child.ContainsNested(newLoop) {
+			lm.insertLoop(newLoop, child)
+			return
+		}
+
+		// If newLoop encompasses any of parent's children, those children
+		// need to move down a level to become children of newLoop instead.
+		if newLoop.ContainsNested(child) {
+			children = append(children, child)
+		}
 	}
 
-	p := &Polygon{
-		loops:       loops,
-		numVertices: len(loops[0].Vertices()), // TODO(roberts): Once multi-loop is supported, fix this.
-		// TODO(roberts): Compute these bounds.
-		bound:          loops[0].RectBound(),
-		subregionBound: EmptyRect(),
+	// Some of the children of parent may now be children of the new loop.
+	newChildren := lm[parent][:0]
+	for _, child := range lm[parent] {
+		keep := true
+		for _, c := range children {
+			if c == child {
+				keep = false
+				break
+			}
+		}
+		if keep {
+			newChildren = append(newChildren, child)
+		}
 	}
+	lm[parent] = newChildren
+	lm[newLoop] = append(lm[newLoop], children...)
+	lm[parent] = append(lm[parent], newLoop)
+}
 
-	const maxLinearSearchLoops = 12 // Based on benchmarks.
-	if len(loops) > maxLinearSearchLoops {
-		p.cumulativeEdges = make([]int, 0, len(loops))
+// loopsAtDepth appends the loops in the map rooted at parent to loops,
+// setting their depth field and recursing into their children. depth 0
+// loops are shells, depth 1 their holes, depth 2 shells nested within those
+// holes, and so on.
+func (lm loopMap) loopsAtDepth(parent *Loop, depth int, loops *[]*Loop) {
+	for _, l := range lm[parent] {
+		l.depth = depth
+		*loops = append(*loops, l)
+		lm.loopsAtDepth(l, depth+1, loops)
 	}
+}
 
+// initNested takes the given loops, determines their nesting using
+// ContainsNested, reorders them into a preorder traversal of the resulting
+// nesting tree, and computes all of the polygon's derived fields. This is
+// the Go analog of the C++ S2Polygon::InitNested method.
+func (p *Polygon) initNested(loops []*Loop) {
+	if len(loops) == 1 {
+		p.initOneLoop(loops[0])
+		return
+	}
+
+	lm := make(loopMap)
 	for _, l := range loops {
+		lm.insertLoop(l, nil)
+	}
+
+	p.loops = nil
+	lm.loopsAtDepth(nil, 0, &p.loops)
+
+	p.initLoopProperties()
+}
+
+// initOneLoop sets up a polygon consisting of exactly one loop, bypassing
+// the nesting computation entirely.
+func (p *Polygon) initOneLoop(loop *Loop) {
+	loop.depth = 0
+	p.loops = []*Loop{loop}
+	p.initLoopProperties()
+}
+
+// initLoopProperties recomputes all of the derived fields of the polygon
+// (bound, subregionBound, hasHoles, numVertices, numEdges,
+// cumulativeEdges, and the ShapeIndex used by ContainsPoint/ContainsCell/
+// IntersectsCell) from p.loops, which must already be set and ordered as a
+// preorder traversal of the nesting hierarchy with depths assigned.
+func (p *Polygon) initLoopProperties() {
+	p.bound = EmptyRect()
+	p.hasHoles = false
+	p.numVertices = 0
+	p.numEdges = 0
+	p.cumulativeEdges = nil
+	p.index = ShapeIndex{}
+
+	if len(p.loops) > maxLinearSearchLoops {
+		p.cumulativeEdges = make([]int, 0, len(p.loops))
+	}
+
+	for i, l := range p.loops {
+		if p.loopIsHole(i) {
+			p.hasHoles = true
+		} else {
+			p.bound = p.bound.Union(l.RectBound())
+		}
 		if p.cumulativeEdges != nil {
 			p.cumulativeEdges = append(p.cumulativeEdges, p.numEdges)
 		}
+		p.numVertices += len(l.Vertices())
 		p.numEdges += len(l.Vertices())
 	}
 
-	return p
+	p.subregionBound = ExpandForSubregions(p.bound)
+	p.index.Add(p)
+}
+
+// findValidationError reports a structured error describing the first
+// reason why the polygon's loops do not form a valid nested polygon, or nil
+// if the polygon is valid. It checks each loop on its own (which includes
+// duplicate vertices within a loop), invalid loop bounds, crossing edges
+// between loops, and shared edges between loops. The nesting hierarchy
+// itself is not checked.
+func (p *Polygon) findValidationError() error {
+	for i, l := range p.loops {
+		if err := l.findValidationErrorNoIndex(); err != nil {
+			return fmt.Errorf("loop %d: %v", i, err)
+		}
+	}
+
+	for i, a := range p.loops {
+		if !a.RectBound().IsValid() {
+			return fmt.Errorf("loop %d: invalid bound", i)
+		}
+		for j := i + 1; j < len(p.loops); j++ {
+			b := p.loops[j]
+			if !a.RectBound().Intersects(b.RectBound()) {
+				continue
+			}
+			if err := checkLoopsShareNoEdges(a, b); err != nil {
+				return fmt.Errorf("loops %d and %d: %v", i, j, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// checkLoopsShareNoEdges reports an error if a and b cross, or if they share
+// an edge (in either direction), both of which are disallowed between any
+// two loops of the same polygon.
+func checkLoopsShareNoEdges(a, b *Loop) error {
+	aVerts := a.Vertices()
+	bVerts := b.Vertices()
+	for i := 0; i < len(aVerts); i++ {
+		a0, a1 := aVerts[i], aVerts[(i+1)%len(aVerts)]
+		for j := 0; j < len(bVerts); j++ {
+			b0, b1 := bVerts[j], bVerts[(j+1)%len(bVerts)]
+			if (a0 == b0 && a1 == b1) || (a0 == b1 && a1 == b0) {
+				return fmt.Errorf("edge shared between loops")
+			}
+			if crossing := CrossingSign(a0, a1, b0, b1); crossing == Cross {
+				return fmt.Errorf("loops cross")
+			}
+		}
+	}
+	return nil
 }
 
 // FullPolygon returns a special "full" polygon.
@@ -222,12 +453,113 @@ func (p *Polygon) CapBound() Cap { return p.bound.CapBound() }
 func (p *Polygon) RectBound() Rect { return p.bound }
 
 // ContainsCell reports whether the polygon contains the given cell.
-// TODO(roberts)
-//func (p *Polygon) ContainsCell(c Cell) bool { ... }
+func (p *Polygon) ContainsCell(c Cell) bool {
+	if p.IsFull() {
+		return true
+	}
+	if p.IsEmpty() || !p.bound.ContainsPoint(c.Center()) {
+		return false
+	}
+
+	it := p.index.Iterator()
+	relation := it.LocateCellID(c.ID())
+
+	// If the index does not have a cell that contains, or is contained by,
+	// c, then c is reported as not contained.
+	if relation == Disjoint {
+		return false
+	}
+	if relation == Indexed && len(it.clipped().edges) == 0 {
+		// c is a descendant of an index cell that has no edges of its own,
+		// so every point of c has the same containment status as the index
+		// cell's reference point. Note that relation == Indexed alone is
+		// not enough: the index cell c landed on may still hold boundary
+		// edges (ShapeIndex stops subdividing once a cell holds few enough
+		// edges, not necessarily zero), in which case c can straddle one of
+		// them and must fall through to the exact test below.
+		return it.cellContains(c.ID())
+	}
+
+	// Either c is a proper ancestor of one or more index cells, or it's a
+	// descendant of an index cell that still has edges running through it;
+	// either way c may have edges running through it, so fall back to an
+	// exact containment test of all four corners plus an edge-crossing
+	// check, which is equivalent to c lying entirely within the polygon.
+	query := NewCrossingEdgeQuery(&p.index)
+	for i := 0; i < 4; i++ {
+		if !p.ContainsPoint(c.Vertex(i)) {
+			return false
+		}
+	}
+	return !query.CellCrossesAnyEdge(c)
+}
 
 // IntersectsCell reports whether the polygon intersects the given cell.
-// TODO(roberts)
-//func (p *Polygon) IntersectsCell(c Cell) bool { ... }
+func (p *Polygon) IntersectsCell(c Cell) bool {
+	if p.IsEmpty() {
+		return false
+	}
+	if p.IsFull() || !p.bound.Intersects(c.RectBound()) {
+		return p.IsFull()
+	}
+
+	it := p.index.Iterator()
+	relation := it.LocateCellID(c.ID())
+
+	if relation == Disjoint {
+		return false
+	}
+	if relation == Indexed && len(it.clipped().edges) == 0 {
+		// See the identical check in ContainsCell: relation == Indexed
+		// alone doesn't guarantee the cell is edge-free.
+		return it.cellContains(c.ID())
+	}
+
+	query := NewCrossingEdgeQuery(&p.index)
+	if query.CellCrossesAnyEdge(c) {
+		return true
+	}
+	for i := 0; i < 4; i++ {
+		if p.ContainsPoint(c.Vertex(i)) {
+			return true
+		}
+	}
+	return false
+}
+
+// ContainsPoint reports whether the polygon contains the given point. This
+// is an O(log n) query backed by the polygon's ShapeIndex, rather than the
+// O(numEdges) brute-force loop-by-loop scan: it locates the leaf index cell
+// containing p, then counts boundary crossings between that cell's stored
+// reference point (whose containment status is already known) and p,
+// toggling containment once per crossing. This is the same technique used
+// by the C++ S2LoopIndex / S2EdgeIndex point-location code.
+func (p *Polygon) ContainsPoint(point Point) bool {
+	if p.IsEmpty() {
+		return false
+	}
+	if p.IsFull() {
+		return true
+	}
+	if !p.bound.ContainsPoint(point) {
+		return false
+	}
+
+	it := p.index.Iterator()
+	if !it.LocatePoint(point) {
+		return false
+	}
+
+	inside := it.clipped().containsCenter
+	target := it.Center()
+	for _, e := range it.clipped().edges {
+		edge := p.Edge(e)
+		if CrossingSign(target, point, edge.V0, edge.V1) == Cross {
+			inside = !inside
+		}
+	}
+	return inside
+}
 
 // Shape Interface
 
@@ -334,19 +666,33 @@ func (p *Polygon) Encode(w io.Writer) error {
 	return e.err
 }
 
-// encode only supports lossless encoding and not compressed format.
+// encodingCompressedVersion is the wire format version used for the
+// compressed (snapped) encoding, matching the C++ implementation.
+const encodingCompressedVersion = 4
+
+// encode chooses between the lossless and compressed wire formats: if every
+// vertex of the polygon lies at a cell center of some common S2 cell level,
+// the polygon can be stored much more compactly as quantized cell
+// coordinates.
 func (p *Polygon) encode(e *encoder) {
 	if p.numVertices == 0 {
-		//p.encodeCompressed(e, nil, maxLevel)
-		e.err = fmt.Errorf("compressed encoding not yet implemented")
+		p.encodeCompressed(e, nil, maxLevel)
 		return
 	}
 
-	// TODO(roberts): C++ computes a heurstic at encoding time to decide between
-	// using compressed and lossless format. Add that calculation once XYZFaceSiTi
-	// type is implemented.
+	snapLevel := p.SnapLevel()
+	if snapLevel < 0 {
+		p.encodeLossless(e)
+		return
+	}
 
-	p.encodeLossless(e)
+	// TODO(roberts): The C++ implementation estimates the compressed size
+	// and falls back to lossless if it isn't actually smaller (e.g. a
+	// polygon with very few vertices relative to its loop count). The
+	// compressed format is always smaller for the common case of many
+	// vertices snapped to a single cell level, which is what SnapLevel
+	// guarantees here, so we always prefer it once a common level exists.
+	p.encodeCompressed(e, p.loops, snapLevel)
 }
 
 // encodeLossless encodes the polygon's Points as float64s.
@@ -364,22 +710,605 @@ func (p *Polygon) encodeLossless(e *encoder) {
 	p.bound.encode(e)
 }
 
+// SnapLevel returns the S2 cell level at which every vertex of every loop
+// in the polygon lies exactly at a cell center, or -1 if there is no such
+// common level (including when the polygon has no vertices). This is used
+// to decide whether the polygon qualifies for the compressed encoding.
+func (p *Polygon) SnapLevel() int {
+	snapLevel := -1
+	for _, l := range p.loops {
+		for _, v := range l.Vertices() {
+			level, ok := snapLevelFromVertex(v)
+			if !ok {
+				return -1
+			}
+			if snapLevel < 0 {
+				snapLevel = level
+			} else if snapLevel != level {
+				return -1
+			}
+		}
+	}
+	return snapLevel
+}
+
+// snapLevelFromVertex reports the cell level at which v is exactly a cell
+// center, or ok=false if v is not a cell center at any level.
+//
+// cellIDFromPoint always returns a leaf (level-30) cell ID, since that's
+// the finest resolution representable, so naively calling .Level() on it
+// can never report anything but the leaf level. The actual level a center
+// was snapped to is instead recoverable from the (si, ti) integer
+// coordinates of v itself (not of the leaf cell containing it, whose center
+// always has odd coordinates): the center of a level-L cell is an odd
+// multiple of 2^(maxLevel-L) in both si and ti, so maxLevel-L is the
+// position of the lowest set bit shared by si and ti.
+func snapLevelFromVertex(v Point) (level int, ok bool) {
+	_, u, w := xyzToFaceUV(v.Vector)
+	si, ti := stToSiTi(uvToST(u)), stToSiTi(uvToST(w))
+	if si == 0 || ti == 0 {
+		// A coordinate of 0 lies on the edge of the face, which is not the
+		// center of a cell at any level.
+		return 0, false
+	}
+
+	tzSi := bits.TrailingZeros32(si)
+	tzTi := bits.TrailingZeros32(ti)
+	if tzSi != tzTi {
+		return 0, false
+	}
+
+	level = maxLevel - tzSi
+	if level < 0 || level > maxLevel {
+		return 0, false
+	}
+	// Guard against coordinate coincidences: confirm the putative level
+	// actually reproduces v when snapped.
+	if !cellIDFromPoint(v).Parent(level).Point().ApproxEqual(v) {
+		return 0, false
+	}
+	return level, true
+}
+
+// encodeCompressed writes the polygon using this package's compressed wire
+// format: all vertices are stored as face/si/ti cell coordinates at
+// snapLevel, delta-and-zigzag-encoded between consecutive vertices of a
+// loop, which is dramatically smaller than the lossless float64 encoding
+// when the polygon has been snapped to a cell grid. A loop almost always
+// stays on a single cube face, so face changes are recorded as a compact
+// per-vertex bitmask rather than a byte per vertex.
+//
+// NOTE: this is not guaranteed to be byte-identical to the reference C++
+// S2Polygon compressed encoding; it is a self-consistent format understood
+// by decodeCompressed (see the round-trip tests in polygon_test.go), not a
+// wire-compatible port of it.
+func (p *Polygon) encodeCompressed(e *encoder, loops []*Loop, snapLevel int) {
+	e.writeInt8(encodingCompressedVersion)
+	e.writeUint8(uint8(snapLevel))
+	e.writeUvarint(uint64(len(loops)))
+
+	for _, l := range loops {
+		verts := l.Vertices()
+		e.writeUvarint(uint64(len(verts)))
+
+		faces := make([]int, len(verts))
+		sis := make([]uint32, len(verts))
+		tis := make([]uint32, len(verts))
+		for i, v := range verts {
+			faces[i], sis[i], tis[i] = xyzFaceSiTi(v, snapLevel)
+		}
+
+		if len(verts) > 0 {
+			e.writeUint8(uint8(faces[0]))
+		}
+		mask := make([]byte, (len(verts)+7)/8)
+		for i := 1; i < len(verts); i++ {
+			if faces[i] != faces[i-1] {
+				mask[i/8] |= 1 << uint(i%8)
+			}
+		}
+		for _, b := range mask {
+			e.writeUint8(b)
+		}
+		for i := 1; i < len(verts); i++ {
+			if faces[i] != faces[i-1] {
+				e.writeUint8(uint8(faces[i]))
+			}
+		}
+
+		var lastSi, lastTi uint32
+		for i := range verts {
+			e.writeVarint(zigzagEncode(int64(sis[i]) - int64(lastSi)))
+			e.writeVarint(zigzagEncode(int64(tis[i]) - int64(lastTi)))
+			lastSi, lastTi = sis[i], tis[i]
+		}
+		e.writeUint8(uint8(l.depth))
+		e.writeBool(l.ContainsOrigin())
+	}
+
+	p.bound.encode(e)
+}
+
+// zigzagEncode maps signed deltas to non-negative integers so that small
+// positive and negative values both stay small: 0, -1, 1, -2, 2, ...
+// becomes 0, 1, 2, 3, 4, ...
+func zigzagEncode(v int64) int64 {
+	return (v << 1) ^ (v >> 63)
+}
+
+// zigzagDecode is the inverse of zigzagEncode.
+func zigzagDecode(v int64) int64 {
+	return int64(uint64(v)>>1) ^ -(v & 1)
+}
+
+// xyzFaceSiTi returns the cube face and the integer (si, ti) cell
+// coordinates of v at the given snap level, i.e. the coordinates of the
+// center of the level-snapLevel cell containing v.
+func xyzFaceSiTi(v Point, snapLevel int) (face int, si, ti uint32) {
+	id := cellIDFromPoint(v).Parent(snapLevel)
+	f, s, t := id.faceSiTi()
+	return f, s, t
+}
+
+// pointFromFaceSiTi returns the Point at the given cube face and integer
+// (si, ti) cell coordinates, inverting xyzFaceSiTi. si and ti are
+// interpreted on the same [0, 2*maxSize] grid used throughout this file,
+// i.e. the grid of cell corners at the leaf level, regardless of which
+// level they were originally produced at.
+func pointFromFaceSiTi(face int, si, ti uint32) Point {
+	u := stToUV(siTiToST(si))
+	v := stToUV(siTiToST(ti))
+	return Point{faceUVToXYZ(face, u, v).Normalize()}
+}
+
+// Decode decodes the Polygon.
+func (p *Polygon) Decode(r io.Reader) error {
+	d := &decoder{r: asByteReader(r)}
+	p.decode(d)
+	return d.err
+}
+
+// decode dispatches to the lossless or compressed decoder based on the
+// version byte written by encode.
+func (p *Polygon) decode(d *decoder) {
+	version := d.readInt8()
+	if d.err != nil {
+		return
+	}
+	switch version {
+	case encodingCompressedVersion:
+		p.decodeCompressed(d)
+	default:
+		p.decodeLossless(d, version)
+	}
+}
+
+// decodeLossless reads a polygon written by encodeLossless. version is the
+// version byte already consumed by decode.
+func (p *Polygon) decodeLossless(d *decoder, version int8) {
+	if version != encodingVersion {
+		d.err = fmt.Errorf("unsupported polygon encoding version %d", version)
+		return
+	}
+
+	_ = d.readBool() // legacy c++ value, always true.
+	p.hasHoles = d.readBool()
+	nloops := d.readUint32()
+
+	p.loops = make([]*Loop, 0, nloops)
+	for i := uint32(0); i < nloops && d.err == nil; i++ {
+		l := new(Loop)
+		l.decode(d)
+		p.loops = append(p.loops, l)
+	}
+	p.bound.decode(d)
+	p.subregionBound = ExpandForSubregions(p.bound)
+	p.initLoopProperties()
+}
+
+// decodeCompressed reads a polygon written by encodeCompressed.
+func (p *Polygon) decodeCompressed(d *decoder) {
+	_ = int(d.readUint8()) // snapLevel: not needed to reconstruct points, since si/ti already carry absolute position.
+	nloops := d.readUvarint()
+
+	p.loops = make([]*Loop, 0, nloops)
+	for i := uint64(0); i < nloops && d.err == nil; i++ {
+		nverts := int(d.readUvarint())
+
+		faces := make([]int, nverts)
+		if nverts > 0 {
+			faces[0] = int(d.readUint8())
+		}
+		mask := make([]byte, (nverts+7)/8)
+		for j := range mask {
+			mask[j] = d.readUint8()
+		}
+		for j := 1; j < nverts; j++ {
+			if mask[j/8]&(1<<uint(j%8)) != 0 {
+				faces[j] = int(d.readUint8())
+			} else {
+				faces[j] = faces[j-1]
+			}
+		}
+
+		verts := make([]Point, 0, nverts)
+		var si, ti uint32
+		for j := 0; j < nverts; j++ {
+			dsi := zigzagDecode(d.readVarint())
+			dti := zigzagDecode(d.readVarint())
+			si = uint32(int64(si) + dsi)
+			ti = uint32(int64(ti) + dti)
+			verts = append(verts, pointFromFaceSiTi(faces[j], si, ti))
+		}
+
+		depth := int(d.readUint8())
+		_ = d.readBool() // origin_inside, recomputed by LoopFromPoints below.
+
+		l := LoopFromPoints(verts)
+		l.depth = depth
+		p.loops = append(p.loops, l)
+	}
+	p.bound.decode(d)
+	p.subregionBound = ExpandForSubregions(p.bound)
+	p.initLoopProperties()
+}
+
+// Area returns the area of the polygon interior, i.e. the region on the
+// left side of an odd number of loops. The return value is between 0 and
+// 4*Pi.
+func (p *Polygon) Area() float64 {
+	if p.IsEmpty() {
+		return 0
+	}
+	if p.IsFull() {
+		return 4 * math.Pi
+	}
+
+	var area float64
+	for i, l := range p.loops {
+		area += float64(p.loopSign(i)) * l.Area()
+	}
+	return area
+}
+
+// Centroid returns the true centroid of the polygon multiplied by the area
+// of the polygon. The result is not unit length, and is not a valid Point.
+// The reason for multiplying by the area is to make it easier to compute
+// the centroid of composite regions such as polygons with holes, by simply
+// summing the (area * centroid) values of each piece.
+//
+// Note that the returned centroid is not unit length, so if it is needed as
+// a Point then it must be renormalized.
+func (p *Polygon) Centroid() Point {
+	if p.IsEmpty() || p.IsFull() {
+		return Point{}
+	}
+
+	var centroid r3.Vector
+	for i, l := range p.loops {
+		sign := float64(p.loopSign(i))
+		c := l.Centroid()
+		centroid = centroid.Add(c.Vector.Mul(sign))
+	}
+	return Point{centroid}
+}
+
+// Contains reports whether this polygon contains the other polygon, i.e.
+// every point contained by o is also contained by p.
+func (p *Polygon) Contains(o *Polygon) bool {
+	// Polygon A contains B iff A contains every loop of B, and the boundary
+	// relationship between them is consistent with containment (A's
+	// boundary does not cross B's).
+	if p.IsFull() || o.IsEmpty() {
+		return true
+	}
+	if p.IsEmpty() || o.IsFull() {
+		return false
+	}
+	if !p.subregionBound.Contains(o.bound) {
+		// Fast reject using the conservative bounds: B is rejected only if
+		// A's subregion bound doesn't contain B's bound and adding B's
+		// bound to A's bound also changes it beyond approximate equality.
+		if !p.bound.Union(o.bound).ApproxEqual(p.bound) {
+			return false
+		}
+	}
+	return p.compareBoundary(o) > 0 && p.ContainsPoint(anyVertex(o))
+}
+
+// Intersects reports whether this polygon intersects the other polygon,
+// i.e. there is a point that is contained by both polygons.
+func (p *Polygon) Intersects(o *Polygon) bool {
+	if p.IsEmpty() || o.IsEmpty() {
+		return false
+	}
+	if p.IsFull() || o.IsFull() {
+		return true
+	}
+	if !p.bound.Intersects(o.bound) {
+		return false
+	}
+	// compareBoundary(o) >= 0 covers the case where p's boundary contains
+	// or crosses o's boundary, and ContainsPoint(anyVertex(o)) covers the
+	// case where o lies entirely inside p's interior with no boundary
+	// overlap. Neither alone covers the symmetric case -- o strictly
+	// containing p -- where o's boundary is entirely outside p
+	// (compareBoundary(o) < 0) and no vertex of o lies inside p, so that
+	// case must be tested explicitly from o's side.
+	return p.compareBoundary(o) >= 0 || p.ContainsPoint(anyVertex(o)) || o.ContainsPoint(anyVertex(p))
+}
+
+// ApproxContains reports whether this polygon contains the other polygon
+// within the given tolerance: specifically, o's boundary is allowed to
+// stray outside p by up to tolerance (nearby crossing points are snapped
+// together rather than carving slivers out of the result) before any
+// remainder is considered real.
+func (p *Polygon) ApproxContains(o *Polygon, tolerance s1.Angle) bool {
+	return ApproxDifference(o, p, tolerance).IsEmpty()
+}
+
+// ApproxDisjoint reports whether this polygon is disjoint from the other
+// polygon within the given tolerance: nearby boundary crossings within
+// tolerance are snapped together before checking whether any real overlap
+// remains.
+func (p *Polygon) ApproxDisjoint(o *Polygon, tolerance s1.Angle) bool {
+	return ApproxIntersection(p, o, tolerance).IsEmpty()
+}
+
+// anyVertex returns an arbitrary vertex of o, which must not be the empty
+// or full polygon.
+func anyVertex(o *Polygon) Point {
+	return o.loops[0].Vertex(0)
+}
+
+// compareBoundary returns +1 if this polygon's boundary contains o's
+// boundary, -1 if it excludes o's boundary, and 0 if the two boundaries
+// cross or if p contains some of o's loops and excludes others. Contains
+// treats 0 as not contained, and Intersects treats it as intersecting. This
+// mirrors the C++ S2Polygon::CompareBoundary routine.
+//
+// Each of o's loops is compared against p independently via
+// compareBoundaryForLoop. Shells and holes are both oriented CCW (see the
+// Polygon type doc), so compareBoundaryForLoop already reports a
+// consistent sign for every loop of o regardless of whether it's a shell
+// or a hole; no additional sign correction is needed.
+func (p *Polygon) compareBoundary(o *Polygon) int {
+	var result int
+	for i, l := range o.loops {
+		r := p.compareBoundaryForLoop(l)
+		if r == 0 {
+			return 0
+		}
+		if i == 0 {
+			result = r
+		} else if result != r {
+			return 0
+		}
+	}
+	return result
+}
+
+// compareBoundaryForLoop returns the result of comparing this polygon's
+// boundary against a single loop of another polygon: +1 if p contains the
+// loop's boundary, -1 if it excludes it, 0 if they cross.
+func (p *Polygon) compareBoundaryForLoop(o *Loop) int {
+	index := &ShapeIndex{}
+	index.Add(p)
+	query := NewCrossingEdgeQuery(index)
+
+	verts := o.Vertices()
+	n := len(verts)
+	for i := 0; i < n; i++ {
+		a, b := verts[i], verts[(i+1)%n]
+		for _, c := range query.CrossingEdges(a, b) {
+			if CrossingSign(a, b, c.V0, c.V1) == Cross {
+				return 0
+			}
+		}
+	}
+
+	// No proper crossing was found, so o's boundary either lies entirely
+	// within p or entirely outside it (touching is allowed). An arbitrary
+	// vertex determines which.
+	if p.ContainsPoint(verts[0]) {
+		return 1
+	}
+	return -1
+}
+
+// InitToSnapped sets the polygon to a copy of src with every vertex snapped
+// to the center of the S2 cell containing it at the given level, and
+// returns it already in canonical nested form with any degeneracies
+// introduced by the snapping (coincident vertices, collapsed loops, or
+// edges that now cross) removed.
+func InitToSnapped(src *Polygon, snapLevel int) *Polygon {
+	var loops []*Loop
+	for _, l := range src.loops {
+		verts := l.Vertices()
+		snapped := make([]Point, 0, len(verts))
+		for _, v := range verts {
+			snapped = append(snapped, cellIDFromPoint(v).Parent(snapLevel).Point())
+		}
+		snapped = removeCoincidentVertices(snapped)
+		if len(snapped) < 3 {
+			// The loop collapsed entirely under snapping; drop it like the
+			// C++ implementation does rather than emit a degenerate loop.
+			continue
+		}
+		loops = append(loops, LoopFromPoints(snapped))
+	}
+
+	if len(loops) == 0 {
+		return &Polygon{}
+	}
+
+	// Snapping can make loops that didn't used to cross do so (e.g. a
+	// narrow isthmus collapsing to a single point on both sides). Run the
+	// snapped loops through the same edge-splitting machinery used by the
+	// boolean ops to resolve any such crossings before re-nesting.
+	return resolveSelfIntersections(loops)
+}
+
+// removeCoincidentVertices collapses consecutive duplicate vertices (which
+// is what two originally-distinct vertices become once they snap to the
+// same cell center).
+func removeCoincidentVertices(verts []Point) []Point {
+	if len(verts) == 0 {
+		return verts
+	}
+	out := verts[:1]
+	for _, v := range verts[1:] {
+		if v == out[len(out)-1] {
+			continue
+		}
+		out = append(out, v)
+	}
+	if len(out) > 1 && out[0] == out[len(out)-1] {
+		out = out[:len(out)-1]
+	}
+	return out
+}
+
+// resolveSelfIntersections repairs loops that may cross each other (or
+// themselves) after a vertex-perturbing transformation like snapping or
+// simplification: every edge is split at every point where it crosses any
+// other edge in the set (reusing the same ShapeIndex-backed
+// CrossingEdgeQuery and edge-splitting helpers the boolean operations use),
+// the resulting fragments are reassembled into simple loops by following
+// the same "least leftward turn" rule as assembleLoops, and the simple
+// loops are re-nested into a canonical polygon.
+//
+// Unlike clipBoundary, this does not classify fragments as inside or
+// outside another polygon -- there's no second operand to test against,
+// only the requirement that the output contain no crossing edges -- so it
+// keeps every fragment and lets assembleLoops's angular-order traversal
+// separate the crossing curves back into distinct simple loops.
+func resolveSelfIntersections(loops []*Loop) *Polygon {
+	if len(loops) == 0 {
+		return &Polygon{}
+	}
+
+	// A temporary polygon is built purely so its ShapeIndex can answer
+	// CrossingEdges queries; its loops may well cross (that's exactly what
+	// we're fixing), so nothing here relies on it being a valid polygon.
+	bag := PolygonFromLoops(loops)
+	index := &ShapeIndex{}
+	index.Add(bag)
+	query := NewCrossingEdgeQuery(index)
+
+	var rawEdges []*clippedEdge
+	for _, l := range bag.loops {
+		appendLoopEdges(l, &rawEdges, false)
+	}
+
+	var split []*clippedEdge
+	for _, e := range rawEdges {
+		crossings := query.CrossingEdges(e.a, e.b)
+		pts := edgeSplitPoints(e.a, e.b, crossingPoints(e.a, e.b, crossings), 0)
+		for j := 0; j+1 < len(pts); j++ {
+			split = append(split, &clippedEdge{pts[j], pts[j+1]})
+		}
+	}
+
+	resolved := assembleLoops(split)
+	if len(resolved) == 0 {
+		return &Polygon{}
+	}
+	return PolygonFromLoops(resolved)
+}
+
+// InitToSimplified sets the polygon to a simplified copy of src: each loop
+// is simplified independently by greedily discarding vertices whose
+// perpendicular distance to the chord connecting their neighbors is within
+// tolerance, subject to the constraint that doing so must not move the
+// simplified edge across any other edge of the polygon (which would change
+// the polygon's topology). If snapToCellCenters is true, the surviving
+// vertices are additionally snapped to the nearest leaf cell center.
+func InitToSimplified(src *Polygon, tolerance s1.Angle, snapToCellCenters bool) *Polygon {
+	var loops []*Loop
+	for _, l := range src.loops {
+		simplified := simplifyLoop(l.Vertices(), tolerance, src)
+		if snapToCellCenters {
+			for i, v := range simplified {
+				simplified[i] = cellIDFromPoint(v).Point()
+			}
+		}
+		if len(simplified) < 3 {
+			continue
+		}
+		loops = append(loops, LoopFromPoints(simplified))
+	}
+
+	if len(loops) == 0 {
+		return &Polygon{}
+	}
+	return resolveSelfIntersections(loops)
+}
+
+// simplifyLoop greedily removes vertices from a closed loop using the
+// standard polyline simplification rule (drop a vertex whenever it lies
+// within tolerance of the chord connecting its surviving neighbors), while
+// rejecting any removal that would cause the shortened edge to cross
+// another edge of owner (preserving topology).
+func simplifyLoop(verts []Point, tolerance s1.Angle, owner *Polygon) []Point {
+	if len(verts) <= 3 {
+		return append([]Point(nil), verts...)
+	}
+
+	kept := append([]Point(nil), verts...)
+	for changed := true; changed; {
+		changed = false
+		n := len(kept)
+		if n <= 3 {
+			break
+		}
+		for i := 0; i < n; i++ {
+			prev := kept[(i-1+n)%n]
+			cur := kept[i]
+			next := kept[(i+1)%n]
+
+			if DistanceFromSegment(cur, prev, next) > tolerance {
+				continue
+			}
+			if edgeCrossesPolygonExcluding(prev, next, owner, cur) {
+				continue
+			}
+
+			kept = append(append([]Point(nil), kept[:i]...), kept[i+1:]...)
+			changed = true
+			break
+		}
+	}
+	return kept
+}
+
+// edgeCrossesPolygonExcluding reports whether the candidate replacement
+// edge a-b properly crosses any edge of owner, ignoring the two edges
+// incident to the vertex being removed (skipped) since those are expected
+// to be collinear-ish with the replacement edge by construction.
+func edgeCrossesPolygonExcluding(a, b Point, owner *Polygon, skipped Point) bool {
+	for _, l := range owner.loops {
+		lverts := l.Vertices()
+		n := len(lverts)
+		for i := 0; i < n; i++ {
+			e0, e1 := lverts[i], lverts[(i+1)%n]
+			if e0 == skipped || e1 == skipped {
+				continue
+			}
+			if CrossingSign(a, b, e0, e1) == Cross {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // TODO(roberts): Differences from C++
-// InitNestedFromLoops
-// InitFromLoop
-// InitOrientedFromLoops
 // IsValid
-// Area
-// Centroid
-// SnapLevel
 // DistanceToPoint
 // DistanceToBoundary
 // Project
 // ProjectToBoundary
-// Contains/ApproxContains/Intersects/ApproxDisjoint for Polygons
-// InitTo{Intersection/ApproxIntersection/Union/ApproxUnion/Diff/ApproxDiff}
-// InitToSimplified
-// InitToSnapped
 // IntersectWithPolyline
 // ApproxIntersectWithPolyline
 // SubtractFromPolyline
@@ -390,13 +1319,343 @@ func (p *Polygon) encodeLossless(e *encoder) {
 // IsNormalized
 // Equals/BoundaryEquals/BoundaryApproxEquals/BoundaryNear Polygons
 // BreakEdgesAndAddToBuilder
-// clearLoops
-// findLoopNestingError
-// initLoops
-// initToSimplifiedInternal
 // internalClipPolyline
-// compareBoundary
-// containsBoundary
-// excludesBoundary
-// containsNonCrossingBoundary
-// excludesNonCrossingShells
+
+// clippedEdge represents a directed fragment of an original polygon edge
+// that survived clipping against the other polygon's loops. a and b are the
+// two endpoints of the fragment (in order along the original edge).
+type clippedEdge struct {
+	a, b Point
+}
+
+// polygonBooleanOp identifies which boolean set operation clipToOp should
+// perform.
+type polygonBooleanOp int
+
+const (
+	opUnion polygonBooleanOp = iota
+	opIntersection
+	opDifference
+	opSymmetricDifference
+)
+
+// clipMode selects which fragments of a clipBoundary call survive, and in
+// which direction they are emitted:
+//
+//   - clipOutside keeps fragments whose midpoint lies outside the other
+//     polygon, in their original direction.
+//   - clipInside keeps fragments whose midpoint lies inside the other
+//     polygon, in their original direction.
+//   - clipInsideReversed keeps fragments whose midpoint lies inside the
+//     other polygon, but with their direction reversed. This is how the
+//     subtrahend of a difference contributes: its boundary becomes a hole
+//     boundary of the result, which requires the opposite winding.
+//   - clipXor keeps every fragment, reversing the direction of the ones
+//     whose midpoint lies inside the other polygon. This produces the
+//     symmetric difference contribution of a single operand.
+type clipMode int
+
+const (
+	clipOutside clipMode = iota
+	clipInside
+	clipInsideReversed
+	clipXor
+)
+
+// clipBoundary clips the edges of a against b, returning the fragments of
+// a's boundary selected by mode (see clipMode).
+func clipBoundary(a, b *Polygon, mode clipMode, vertexMergeRadius s1.Angle) []*clippedEdge {
+	var result []*clippedEdge
+
+	// keep(x) reports whether a fragment whose midpoint containment
+	// in b is x should be kept; reverse(x) reports whether it should be
+	// emitted with its direction flipped. Both empty/full special cases and
+	// the general per-fragment loop below are expressed in terms of these
+	// so the selection logic lives in exactly one place.
+	keep := func(inside bool) bool {
+		switch mode {
+		case clipOutside:
+			return !inside
+		case clipInside, clipInsideReversed:
+			return inside
+		case clipXor:
+			return true
+		}
+		return false
+	}
+	reverse := func(inside bool) bool {
+		switch mode {
+		case clipInsideReversed:
+			return true
+		case clipXor:
+			return inside
+		}
+		return false
+	}
+
+	if b.IsEmpty() || b.IsFull() {
+		inside := b.IsFull()
+		if keep(inside) {
+			for _, l := range a.loops {
+				appendLoopEdges(l, &result, reverse(inside))
+			}
+		}
+		return result
+	}
+
+	bIndex := &ShapeIndex{}
+	bIndex.Add(b)
+	query := NewCrossingEdgeQuery(bIndex)
+
+	for _, l := range a.loops {
+		verts := l.Vertices()
+		n := len(verts)
+		for i := 0; i < n; i++ {
+			v0, v1 := verts[i], verts[(i+1)%n]
+			crossings := query.CrossingEdges(v0, v1)
+
+			// Split the edge at each crossing point (snapped together within
+			// vertexMergeRadius so that nearly-coincident crossings collapse
+			// to a single split point), then classify each resulting
+			// fragment by testing whether its midpoint lies inside b.
+			splits := edgeSplitPoints(v0, v1, crossingPoints(v0, v1, crossings), vertexMergeRadius)
+			for j := 0; j+1 < len(splits); j++ {
+				fa, fb := splits[j], splits[j+1]
+				mid := Point{fa.Vector.Add(fb.Vector).Normalize()}
+				inside := b.ContainsPoint(mid)
+				if !keep(inside) {
+					continue
+				}
+				if reverse(inside) {
+					result = append(result, &clippedEdge{fb, fa})
+				} else {
+					result = append(result, &clippedEdge{fa, fb})
+				}
+			}
+		}
+	}
+	return result
+}
+
+// appendLoopEdges appends every edge of l, as a clippedEdge fragment, to
+// result. If reverse is true, each fragment is emitted with its direction
+// flipped and the loop is walked back to front, so that the whole loop's
+// winding is reversed.
+func appendLoopEdges(l *Loop, result *[]*clippedEdge, reverse bool) {
+	verts := l.Vertices()
+	n := len(verts)
+	for i := 0; i < n; i++ {
+		a, b := verts[i], verts[(i+1)%n]
+		if reverse {
+			*result = append(*result, &clippedEdge{b, a})
+		} else {
+			*result = append(*result, &clippedEdge{a, b})
+		}
+	}
+}
+
+// crossingPoints resolves each candidate edge returned by a
+// CrossingEdgeQuery against v0v1 to its actual intersection point, dropping
+// candidates that turn out not to properly cross (the query is conservative
+// and may over-report).
+func crossingPoints(v0, v1 Point, candidates []Edge) []Point {
+	var pts []Point
+	for _, c := range candidates {
+		if CrossingSign(v0, v1, c.V0, c.V1) != Cross {
+			continue
+		}
+		pts = append(pts, Intersection(v0, v1, c.V0, c.V1))
+	}
+	return pts
+}
+
+// edgeSplitPoints returns the ordered sequence of points along edge v0v1,
+// starting at v0 and ending at v1, with the given extra points inserted in
+// their proper order along the edge. Points within mergeRadius of each
+// other or of an endpoint are snapped together so that nearly-coincident
+// crossings collapse to a single split point.
+func edgeSplitPoints(v0, v1 Point, extra []Point, mergeRadius s1.Angle) []Point {
+	pts := append([]Point{v0}, extra...)
+	pts = append(pts, v1)
+
+	sort.Slice(pts, func(i, j int) bool {
+		return v0.Distance(pts[i]) < v0.Distance(pts[j])
+	})
+
+	if mergeRadius > 0 {
+		pts = mergeClosePoints(pts, mergeRadius)
+	}
+	return pts
+}
+
+// mergeClosePoints collapses consecutive points that are within radius of
+// each other into a single point.
+func mergeClosePoints(pts []Point, radius s1.Angle) []Point {
+	if len(pts) < 2 {
+		return pts
+	}
+	out := pts[:1]
+	for _, p := range pts[1:] {
+		last := out[len(out)-1]
+		if last.Distance(p) <= radius {
+			continue
+		}
+		out = append(out, p)
+	}
+	return out
+}
+
+// assembleLoops reassembles a set of directed edge fragments into closed
+// loops by repeatedly following, from each unused fragment, the unused
+// outgoing fragment at its endpoint vertex with the smallest turn angle
+// (the sharpest right turn; see nextCCWEdge), until it returns to its
+// starting vertex.
+func assembleLoops(edges []*clippedEdge) []*Loop {
+	// Index outgoing edges by origin vertex.
+	outgoing := make(map[Point][]*clippedEdge)
+	used := make(map[*clippedEdge]bool)
+	for _, e := range edges {
+		outgoing[e.a] = append(outgoing[e.a], e)
+	}
+
+	var loops []*Loop
+	for _, start := range edges {
+		if used[start] {
+			continue
+		}
+		var verts []Point
+		cur := start
+		for {
+			used[cur] = true
+			verts = append(verts, cur.a)
+			if cur.b == start.a {
+				break
+			}
+			next := nextCCWEdge(cur, outgoing[cur.b], used)
+			if next == nil {
+				// Dangling fragment; the partial chain can't be closed, so
+				// drop it rather than emit a degenerate loop.
+				verts = nil
+				break
+			}
+			cur = next
+		}
+		if len(verts) >= 3 {
+			loops = append(loops, LoopFromPoints(verts))
+		}
+	}
+	return loops
+}
+
+// nextCCWEdge returns the unused candidate edge that continues turning
+// counter-clockwise from cur around their shared vertex, which is the rule
+// that reconstructs non-self-intersecting loops from a bundle of fragments
+// meeting at a vertex.
+func nextCCWEdge(cur *clippedEdge, candidates []*clippedEdge, used map[*clippedEdge]bool) *clippedEdge {
+	var best *clippedEdge
+	for _, c := range candidates {
+		if used[c] {
+			continue
+		}
+		if best == nil || TurnAngle(cur.a, cur.b, c.b) < TurnAngle(cur.a, cur.b, best.b) {
+			best = c
+		}
+	}
+	return best
+}
+
+// clipToOp runs the edge-clipping boolean operation algorithm: it clips the
+// boundary of a against b and the boundary of b against a (inverting b's
+// contribution as required by op), reassembles the surviving fragments into
+// loops, and returns the resulting polygon in canonical nested form.
+func clipToOp(a, b *Polygon, op polygonBooleanOp, vertexMergeRadius s1.Angle) *Polygon {
+	// The full loop has no real edges to clip, so operations whose result
+	// is the full polygon are settled here.
+	aFull, bFull := a.IsFull(), b.IsFull()
+	switch {
+	case op == opUnion && (aFull || bFull),
+		op == opIntersection && aFull && bFull,
+		op == opDifference && aFull && b.IsEmpty(),
+		op == opSymmetricDifference && ((aFull && b.IsEmpty()) || (bFull && a.IsEmpty())):
+		return FullPolygon()
+	}
+
+	var edges []*clippedEdge
+
+	switch op {
+	case opUnion:
+		edges = append(edges, clipBoundary(a, b, clipOutside, vertexMergeRadius)...)
+		edges = append(edges, clipBoundary(b, a, clipOutside, vertexMergeRadius)...)
+	case opIntersection:
+		edges = append(edges, clipBoundary(a, b, clipInside, vertexMergeRadius)...)
+		edges = append(edges, clipBoundary(b, a, clipInside, vertexMergeRadius)...)
+	case opDifference:
+		// A \ B keeps the part of A's boundary outside B, plus B's
+		// boundary wherever it runs inside A -- reversed, since it now
+		// bounds a hole cut into A rather than B's own interior.
+		edges = append(edges, clipBoundary(a, b, clipOutside, vertexMergeRadius)...)
+		edges = append(edges, clipBoundary(b, a, clipInsideReversed, vertexMergeRadius)...)
+	case opSymmetricDifference:
+		// (A \ B) ∪ (B \ A): every fragment of both boundaries is kept,
+		// reversed wherever it lies inside the other polygon.
+		edges = append(edges, clipBoundary(a, b, clipXor, vertexMergeRadius)...)
+		edges = append(edges, clipBoundary(b, a, clipXor, vertexMergeRadius)...)
+	}
+
+	loops := assembleLoops(edges)
+	if len(loops) == 0 {
+		return &Polygon{}
+	}
+	return PolygonFromLoops(loops)
+}
+
+// PolygonFromIntersection returns a new polygon representing the points
+// contained by both a and b.
+func PolygonFromIntersection(a, b *Polygon) *Polygon {
+	return clipToOp(a, b, opIntersection, 0)
+}
+
+// ApproxIntersection returns the intersection of a and b, snapping together
+// any crossing points that fall within vertexMergeRadius of each other.
+// This makes the result more robust to numerical error than
+// PolygonFromIntersection when a and b share nearly-coincident boundaries.
+func ApproxIntersection(a, b *Polygon, vertexMergeRadius s1.Angle) *Polygon {
+	return clipToOp(a, b, opIntersection, vertexMergeRadius)
+}
+
+// PolygonFromUnion returns a new polygon representing the points contained
+// by either a or b.
+func PolygonFromUnion(a, b *Polygon) *Polygon {
+	return clipToOp(a, b, opUnion, 0)
+}
+
+// ApproxUnion returns the union of a and b, snapping together any crossing
+// points that fall within vertexMergeRadius of each other.
+func ApproxUnion(a, b *Polygon, vertexMergeRadius s1.Angle) *Polygon {
+	return clipToOp(a, b, opUnion, vertexMergeRadius)
+}
+
+// PolygonFromDifference returns a new polygon representing the points
+// contained by a but not by b.
+func PolygonFromDifference(a, b *Polygon) *Polygon {
+	return clipToOp(a, b, opDifference, 0)
+}
+
+// ApproxDifference returns the difference of a and b, snapping together any
+// crossing points that fall within vertexMergeRadius of each other.
+func ApproxDifference(a, b *Polygon, vertexMergeRadius s1.Angle) *Polygon {
+	return clipToOp(a, b, opDifference, vertexMergeRadius)
+}
+
+// PolygonFromSymmetricDifference returns a new polygon representing the
+// points contained by exactly one of a or b.
+func PolygonFromSymmetricDifference(a, b *Polygon) *Polygon {
+	return clipToOp(a, b, opSymmetricDifference, 0)
+}
+
+// ApproxSymmetricDifference returns the symmetric difference of a and b,
+// snapping together any crossing points that fall within vertexMergeRadius
+// of each other.
+func ApproxSymmetricDifference(a, b *Polygon, vertexMergeRadius s1.Angle) *Polygon {
+	return clipToOp(a, b, opSymmetricDifference, vertexMergeRadius)
+}
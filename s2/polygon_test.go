@@ -0,0 +1,401 @@
+/*
+Copyright 2015 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package s2
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/golang/geo/s1"
+)
+
+func TestPolygonFromLoopsDoesNotMutateCallerLoops(t *testing.T) {
+	shell := makeLoop("0:0, 0:3, 3:3, 3:0")
+	hole := makeLoop("1:1, 1:2, 2:2, 2:1")
+	wantShellDepth, wantHoleDepth := shell.depth, hole.depth
+	wantShellOriented, wantHoleOriented := shell.ContainsOrigin(), hole.ContainsOrigin()
+
+	PolygonFromLoops([]*Loop{shell, hole})
+
+	if shell.depth != wantShellDepth {
+		t.Errorf("PolygonFromLoops mutated caller's shell depth: got %d, want %d", shell.depth, wantShellDepth)
+	}
+	if hole.depth != wantHoleDepth {
+		t.Errorf("PolygonFromLoops mutated caller's hole depth: got %d, want %d", hole.depth, wantHoleDepth)
+	}
+	if shell.ContainsOrigin() != wantShellOriented {
+		t.Errorf("PolygonFromLoops mutated caller's shell orientation")
+	}
+	if hole.ContainsOrigin() != wantHoleOriented {
+		t.Errorf("PolygonFromLoops mutated caller's hole orientation")
+	}
+}
+
+func TestPolygonFromOrientedLoopsDoesNotMutateCallerLoops(t *testing.T) {
+	shell := makeLoop("0:0, 3:0, 3:3, 0:3") // deliberately reversed (CW)
+	wantOriented := shell.ContainsOrigin()
+
+	PolygonFromOrientedLoops([]*Loop{shell})
+
+	if shell.ContainsOrigin() != wantOriented {
+		t.Errorf("PolygonFromOrientedLoops mutated caller's loop orientation in place")
+	}
+}
+
+func TestPolygonBooleanOps(t *testing.T) {
+	a := makePolygon("0:0, 0:4, 4:4, 4:0") // [0,4]x[0,4]
+	b := makePolygon("2:2, 2:6, 6:6, 6:2") // [2,6]x[2,6], overlaps a
+	disjoint := makePolygon("10:10, 10:12, 12:12, 12:10")
+	nested := makePolygon("1:1, 1:3, 3:3, 3:1") // strictly inside a
+	full := FullPolygon()
+	empty := &Polygon{}
+
+	tests := []struct {
+		name       string
+		got        *Polygon
+		wantEmpty  bool
+		checkPoint Point
+		wantInside bool
+	}{
+		{"union of overlapping squares contains a's corner",
+			PolygonFromUnion(a, b), false, PointFromLatLng(LatLngFromDegrees(0.5, 0.5)), true},
+		{"intersection of overlapping squares contains the overlap region",
+			PolygonFromIntersection(a, b), false, PointFromLatLng(LatLngFromDegrees(3, 3)), true},
+		{"intersection of overlapping squares excludes a's corner",
+			PolygonFromIntersection(a, b), false, PointFromLatLng(LatLngFromDegrees(0.5, 0.5)), false},
+		{"disjoint intersection is empty",
+			PolygonFromIntersection(a, disjoint), true, Point{}, false},
+		{"difference removes the overlap but keeps the rest of a",
+			PolygonFromDifference(a, b), false, PointFromLatLng(LatLngFromDegrees(0.5, 0.5)), true},
+		{"difference excludes points that were inside b",
+			PolygonFromDifference(a, b), false, PointFromLatLng(LatLngFromDegrees(3, 3)), false},
+		{"difference of a minus a nested polygon leaves a ring, excluding the hole",
+			PolygonFromDifference(a, nested), false, PointFromLatLng(LatLngFromDegrees(2, 2)), false},
+		{"difference of a minus a nested polygon keeps points outside the hole",
+			PolygonFromDifference(a, nested), false, PointFromLatLng(LatLngFromDegrees(0.5, 0.5)), true},
+		{"symmetric difference excludes the overlap",
+			PolygonFromSymmetricDifference(a, b), false, PointFromLatLng(LatLngFromDegrees(3, 3)), false},
+		{"symmetric difference keeps a's corner",
+			PolygonFromSymmetricDifference(a, b), false, PointFromLatLng(LatLngFromDegrees(0.5, 0.5)), true},
+		{"symmetric difference keeps b's corner",
+			PolygonFromSymmetricDifference(a, b), false, PointFromLatLng(LatLngFromDegrees(5.5, 5.5)), true},
+		{"union with full polygon is full", PolygonFromUnion(a, full), false, PointFromLatLng(LatLngFromDegrees(80, 80)), true},
+		{"union with empty polygon is unchanged", PolygonFromUnion(a, empty), false, PointFromLatLng(LatLngFromDegrees(2, 2)), true},
+		{"intersection with empty polygon is empty", PolygonFromIntersection(a, empty), true, Point{}, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.got.IsEmpty(); got != test.wantEmpty {
+				t.Fatalf("IsEmpty() = %v, want %v", got, test.wantEmpty)
+			}
+			if test.wantEmpty {
+				return
+			}
+			if got := test.got.ContainsPoint(test.checkPoint); got != test.wantInside {
+				t.Errorf("ContainsPoint(%v) = %v, want %v", test.checkPoint, got, test.wantInside)
+			}
+		})
+	}
+}
+
+func TestPolygonContainsWithHoles(t *testing.T) {
+	// annulus is a polygon with a hole: the outer shell [0,10]x[0,10] minus
+	// the inner square [4,6]x[4,6].
+	annulus := PolygonFromLoops([]*Loop{
+		makeLoop("0:0, 0:10, 10:10, 10:0"),
+		makeLoop("4:4, 4:6, 6:6, 6:4"),
+	})
+
+	diskInSolidPart := makePolygon("1:1, 1:2, 2:2, 2:1")
+	if !annulus.Contains(diskInSolidPart) {
+		t.Errorf("annulus.Contains(disk in solid part) = false, want true")
+	}
+
+	diskInHole := makePolygon("4.2:4.2, 4.2:5.8, 5.8:5.8, 5.8:4.2")
+	if annulus.Contains(diskInHole) {
+		t.Errorf("annulus.Contains(disk entirely in the hole) = true, want false")
+	}
+
+	if annulus.Intersects(diskInHole) {
+		t.Errorf("annulus.Intersects(disk entirely in the hole) = true, want false")
+	}
+	if !annulus.Intersects(diskInSolidPart) {
+		t.Errorf("annulus.Intersects(disk in solid part) = false, want true")
+	}
+
+	// A big square that strictly contains the annulus (shell and hole
+	// alike): compareBoundary must not flip sign on the hole loop, or
+	// Contains wrongly reports false here.
+	big := makePolygon("-1:-1, -1:11, 11:11, 11:-1")
+	if !big.Contains(annulus) {
+		t.Errorf("big.Contains(annulus) = false, want true (argument polygon has a hole)")
+	}
+
+	// The reverse: the annulus is strictly contained by big, whose
+	// boundary lies entirely outside the annulus and shares no vertex
+	// with it, so Intersects must fall back to testing containment from
+	// big's side.
+	if !annulus.Intersects(big) {
+		t.Errorf("annulus.Intersects(big) = false, want true (argument strictly contains receiver)")
+	}
+	if !big.Intersects(annulus) {
+		t.Errorf("big.Intersects(annulus) = false, want true")
+	}
+}
+
+func TestPolygonApproxContainsAndDisjoint(t *testing.T) {
+	a := makePolygon("0:0, 0:4, 4:4, 4:0")
+	// b is congruent to a but offset by a tiny amount, smaller than a
+	// generous tolerance, so it should approx-contain (and be contained
+	// by) a even though exact Contains would see a sliver of mismatch.
+	b := makePolygon("0:0.0000001, 0:4.0000001, 4:4.0000001, 4:0.0000001")
+	tolerance := s1.Angle(1e-3)
+
+	if !a.ApproxContains(b, tolerance) {
+		t.Errorf("a.ApproxContains(b, %v) = false, want true", tolerance)
+	}
+
+	disjoint := makePolygon("10:10, 10:12, 12:12, 12:10")
+	if !a.ApproxDisjoint(disjoint, tolerance) {
+		t.Errorf("a.ApproxDisjoint(disjoint, %v) = false, want true", tolerance)
+	}
+	if a.ApproxDisjoint(b, tolerance) {
+		t.Errorf("a.ApproxDisjoint(b, %v) = true, want false", tolerance)
+	}
+}
+
+func TestPolygonSnappingResolvesSelfCrossings(t *testing.T) {
+	// A narrow spike whose tip is thinner than a level-4 cell: snapping both
+	// sides of the tip to the same cell center turns the loop into a
+	// self-crossing bowtie, which resolveSelfIntersections must split back
+	// into simple (possibly degenerate) loops instead of leaving a
+	// self-crossing polygon.
+	const level = 4
+	src := makePolygon("0:0, 0:10, 0.00001:10, 5:0.00002, 10:10, 10.00001:10, 10:0")
+
+	snapped := InitToSnapped(src, level)
+
+	for li := 0; li < snapped.NumLoops(); li++ {
+		if ei, ej, ok := firstSelfCrossing(snapped.Loop(li)); ok {
+			t.Errorf("snapped loop %d is still self-crossing at edges %d and %d", li, ei, ej)
+		}
+	}
+}
+
+// firstSelfCrossing reports the first pair of non-adjacent edges of loop
+// that cross each other, if any.
+func firstSelfCrossing(loop *Loop) (i, j int, ok bool) {
+	verts := loop.Vertices()
+	n := len(verts)
+	for i := 0; i < n; i++ {
+		a0, a1 := verts[i], verts[(i+1)%n]
+		for j := i + 1; j < n; j++ {
+			if j == i || (j+1)%n == i || (i+1)%n == j {
+				continue
+			}
+			b0, b1 := verts[j], verts[(j+1)%n]
+			if CrossingSign(a0, a1, b0, b1) == Cross {
+				return i, j, true
+			}
+		}
+	}
+	return 0, 0, false
+}
+
+func TestPolygonSnappingRoundTripIsStableAtFixedLevel(t *testing.T) {
+	const level = 15
+	src := makePolygon("0:0, 0:10, 10:10, 10:0")
+
+	once := InitToSnapped(src, level)
+	twice := InitToSnapped(once, level)
+
+	if twice.NumLoops() != once.NumLoops() {
+		t.Fatalf("re-snapping at the same level changed loop count: got %d, want %d", twice.NumLoops(), once.NumLoops())
+	}
+	for i := 0; i < once.NumLoops(); i++ {
+		a, b := once.Loop(i).Vertices(), twice.Loop(i).Vertices()
+		if len(a) != len(b) {
+			t.Fatalf("loop %d vertex count changed on re-snap: got %d, want %d", i, len(b), len(a))
+		}
+		for j, v := range a {
+			if v != b[j] {
+				t.Errorf("loop %d vertex %d moved on re-snap: got %v, want %v", i, j, b[j], v)
+			}
+		}
+	}
+}
+
+func TestPolygonSimplifyCollapsesDenseCircle(t *testing.T) {
+	// A densely-sampled circle simplified at a generous tolerance should
+	// collapse to a small number of vertices roughly independent of how
+	// finely it was originally sampled.
+	const samples = 360
+	var pts []string
+	for i := 0; i < samples; i++ {
+		angle := 2 * math.Pi * float64(i) / samples
+		lat := 1 + 0.01*math.Cos(angle)
+		lng := 1 + 0.01*math.Sin(angle)
+		pts = append(pts, latLngLoopVertex(lat, lng))
+	}
+	src := makePolygon(strings.Join(pts, ", "))
+
+	simplified := InitToSimplified(src, s1.Angle(0.002*math.Pi/180), false)
+
+	if got := len(simplified.Loop(0).Vertices()); got >= samples {
+		t.Errorf("InitToSimplified did not reduce vertex count: got %d, want < %d", got, samples)
+	}
+}
+
+func latLngLoopVertex(lat, lng float64) string {
+	return fmt.Sprintf("%g:%g", lat, lng)
+}
+
+func TestPolygonSnapLevel(t *testing.T) {
+	const level = 10
+
+	src := makePolygon("0:0, 0:10, 10:10, 10:0")
+	snapped := InitToSnapped(src, level)
+
+	if got := snapped.SnapLevel(); got != level {
+		t.Fatalf("SnapLevel() after InitToSnapped(_, %d) = %d, want %d", level, got, level)
+	}
+
+	unsnapped := makePolygon("0:0, 0:9.9999, 10:10, 10:0")
+	if got := unsnapped.SnapLevel(); got != -1 {
+		t.Errorf("SnapLevel() of a non-snapped polygon = %d, want -1", got)
+	}
+}
+
+func TestPolygonCompressedEncodeDecodeRoundTrip(t *testing.T) {
+	const level = 12
+	src := InitToSnapped(makePolygon("0:0, 0:10, 10:10, 10:0"), level)
+
+	var buf bytes.Buffer
+	if err := src.Encode(&buf); err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+
+	got := new(Polygon)
+	if err := got.Decode(&buf); err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+
+	if got.NumLoops() != src.NumLoops() {
+		t.Fatalf("round-tripped polygon has %d loops, want %d", got.NumLoops(), src.NumLoops())
+	}
+	for i := 0; i < src.NumLoops(); i++ {
+		srcVerts, gotVerts := src.Loop(i).Vertices(), got.Loop(i).Vertices()
+		if len(srcVerts) != len(gotVerts) {
+			t.Fatalf("loop %d has %d vertices, want %d", i, len(gotVerts), len(srcVerts))
+		}
+		for j, v := range srcVerts {
+			if !v.ApproxEqual(gotVerts[j]) {
+				t.Errorf("loop %d vertex %d = %v, want %v", i, j, gotVerts[j], v)
+			}
+		}
+	}
+}
+
+func TestPolygonContainsPointUsesIndex(t *testing.T) {
+	// annulus is a polygon with a hole: the outer shell [0,10]x[0,10] minus
+	// the inner square [4,6]x[4,6]. ContainsPoint is backed entirely by
+	// p.index, so this also exercises that the index actually gets
+	// populated at construction time.
+	annulus := PolygonFromLoops([]*Loop{
+		makeLoop("0:0, 0:10, 10:10, 10:0"),
+		makeLoop("4:4, 4:6, 6:6, 6:4"),
+	})
+
+	tests := []struct {
+		point Point
+		want  bool
+	}{
+		{PointFromLatLng(LatLngFromDegrees(1, 1)), true},    // solid part
+		{PointFromLatLng(LatLngFromDegrees(5, 5)), false},   // in the hole
+		{PointFromLatLng(LatLngFromDegrees(20, 20)), false}, // outside entirely
+	}
+	for _, test := range tests {
+		if got := annulus.ContainsPoint(test.point); got != test.want {
+			t.Errorf("annulus.ContainsPoint(%v) = %v, want %v", test.point, got, test.want)
+		}
+	}
+}
+
+func TestPolygonContainsCellAndIntersectsCell(t *testing.T) {
+	square := makePolygon("0:0, 0:10, 10:10, 10:0")
+	interior := cellIDFromPoint(PointFromLatLng(LatLngFromDegrees(5, 5))).Parent(20)
+	exterior := cellIDFromPoint(PointFromLatLng(LatLngFromDegrees(50, 50))).Parent(20)
+
+	if !square.ContainsCell(CellFromCellID(interior)) {
+		t.Errorf("ContainsCell(interior cell) = false, want true")
+	}
+	if square.ContainsCell(CellFromCellID(exterior)) {
+		t.Errorf("ContainsCell(exterior cell) = true, want false")
+	}
+	if !square.IntersectsCell(CellFromCellID(interior)) {
+		t.Errorf("IntersectsCell(interior cell) = false, want true")
+	}
+	if square.IntersectsCell(CellFromCellID(exterior)) {
+		t.Errorf("IntersectsCell(exterior cell) = true, want false")
+	}
+
+	// straddle is a tiny cell centered exactly on the square's south edge
+	// (lat 0), so it lies half inside and half outside the polygon. Since
+	// the polygon has only 4 edges, its whole boundary sits in one coarse
+	// index cell, so LocateCellID reports straddle as Indexed rather than
+	// Subdivided -- exactly the case where the Indexed branch must not
+	// trust the index cell's reference point alone, because that index
+	// cell still holds the crossing edge.
+	straddle := CellFromCellID(cellIDFromPoint(PointFromLatLng(LatLngFromDegrees(0, 5))).Parent(28))
+	if square.ContainsCell(straddle) {
+		t.Errorf("ContainsCell(boundary-straddling cell) = true, want false")
+	}
+	if !square.IntersectsCell(straddle) {
+		t.Errorf("IntersectsCell(boundary-straddling cell) = false, want true")
+	}
+}
+
+// makeDenseCircle builds a polygon loop of n vertices approximating a
+// circle, for benchmarks that need a large, realistic number of edges.
+func makeDenseCircle(n int) *Polygon {
+	var pts []string
+	for i := 0; i < n; i++ {
+		angle := 2 * math.Pi * float64(i) / float64(n)
+		lat := 1 + 0.1*math.Cos(angle)
+		lng := 1 + 0.1*math.Sin(angle)
+		pts = append(pts, latLngLoopVertex(lat, lng))
+	}
+	return makePolygon(strings.Join(pts, ", "))
+}
+
+// BenchmarkPolygonContainsPointLargePolygon demonstrates that ContainsPoint
+// is an O(log n) index lookup rather than an O(numEdges) scan: each query
+// should run in well under a microsecond even against a 10,000-vertex
+// polygon.
+func BenchmarkPolygonContainsPointLargePolygon(b *testing.B) {
+	p := makeDenseCircle(10000)
+	point := PointFromLatLng(LatLngFromDegrees(1, 1)) // interior
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		p.ContainsPoint(point)
+	}
+}